@@ -0,0 +1,119 @@
+// Command terraform-cleaner scans a directory tree for Terraform modules and
+// reports variables, locals, modules, outputs, and data blocks that are
+// declared but never referenced.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	tfclean "github.com/stobias123/terraform-cleaner/terraform"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var include, exclude tfclean.RepeatablePatterns
+	format := flag.String("format", "text", "output format: text, json, or sarif")
+	check := flag.Bool("check", false, "prune mode: exit non-zero if any declaration would be removed, without editing files")
+	diff := flag.Bool("diff", false, "prune mode: print a unified diff of the declarations that would be removed")
+	write := flag.Bool("write", false, "prune mode: remove unused declarations and persist the result to disk")
+	flag.Var(&include, "include", "glob pattern a module path must match to be scanned (repeatable)")
+	flag.Var(&exclude, "exclude", "glob pattern a module path must not match to be scanned (repeatable)")
+	flag.Parse()
+
+	root := "."
+	if flag.NArg() > 0 {
+		root = flag.Arg(0)
+	}
+
+	scanner := &tfclean.Scanner{Root: root, Include: include, Exclude: exclude}
+	dirs, err := scanner.Discover()
+	if err != nil {
+		return err
+	}
+
+	var sortedDirs []string
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+
+	if *check || *diff || *write {
+		return prune(sortedDirs, *check, *diff, *write)
+	}
+
+	reporter, err := reporterFor(*format)
+	if err != nil {
+		return err
+	}
+
+	var reports []*tfclean.Report
+	for _, dir := range sortedDirs {
+		mu, err := tfclean.NewModuleUsage(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+		reports = append(reports, tfclean.NewReport(mu))
+	}
+
+	return reporter.Write(os.Stdout, reports)
+}
+
+// prune runs ModuleUsage.Prune over every module in dirs, mirroring
+// terraform fmt's -check/-diff/-write UX: -check reports what would change
+// and exits non-zero without touching disk, -diff additionally (or instead)
+// prints a unified diff per removed declaration, and -write persists the
+// result. Passing -check or -diff without -write is a dry run.
+func prune(dirs []string, check, diff, write bool) error {
+	pruned := false
+
+	for _, dir := range dirs {
+		mu, err := tfclean.NewModuleUsage(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+
+		changes, err := mu.Prune(!write, diff)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+
+		for _, change := range changes {
+			pruned = true
+			if change.Note != "" {
+				fmt.Printf("%s: %s %q: %s\n", change.File, change.Kind, change.Name, change.Note)
+				continue
+			}
+			fmt.Printf("%s: removed unused %s %q\n", change.File, change.Kind, change.Name)
+			if diff && change.Diff != "" {
+				fmt.Print(change.Diff)
+			}
+		}
+	}
+
+	if check && pruned {
+		return fmt.Errorf("unused declarations found")
+	}
+	return nil
+}
+
+func reporterFor(format string) (tfclean.Reporter, error) {
+	switch format {
+	case "text":
+		return tfclean.TextReporter{}, nil
+	case "json":
+		return tfclean.JSONReporter{}, nil
+	case "sarif":
+		return tfclean.SARIFReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown -format %q: want text, json, or sarif", format)
+	}
+}
@@ -0,0 +1,122 @@
+package terraform
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// Scanner discovers module directories under Root, honoring Include/Exclude
+// doublestar glob patterns (e.g. "**/modules/**", "envs/prod/**") matched
+// against each candidate path relative to Root. Excludes are evaluated after
+// includes and short-circuit directory descent, so something like
+// "**/.terraform/**" keeps a large vendored tree from being walked at all.
+type Scanner struct {
+	Root    string
+	Include []string
+	Exclude []string
+}
+
+// NewScanner returns a Scanner over root with no include/exclude filtering.
+func NewScanner(root string) *Scanner {
+	return &Scanner{Root: root}
+}
+
+// Discover walks Root and returns the set of directories containing at
+// least one .tf or .tf.json file that survives Include/Exclude filtering.
+func (s *Scanner) Discover() (map[string]bool, error) {
+	directories := map[string]bool{}
+
+	err := filepath.WalkDir(s.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.Root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && s.isExcluded(rel) {
+				log.Debugf("Skipping excluded directory: %s\n", path)
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !isTfFile(d.Name()) {
+			return nil
+		}
+		if !s.isIncluded(rel) || s.isExcluded(rel) {
+			return nil
+		}
+
+		module := filepath.Dir(path)
+		log.Debugf("Visited: %s\n", module)
+		directories[module] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return directories, nil
+}
+
+func (s *Scanner) isIncluded(rel string) bool {
+	if len(s.Include) == 0 {
+		return true
+	}
+	return matchesAny(s.Include, rel)
+}
+
+// isExcluded evaluates Exclude in order, gitignore-style: a plain pattern
+// excludes any path it matches, and a "!"-prefixed pattern rescues a path
+// that an earlier pattern excluded. Patterns are evaluated in slice order,
+// so a later pattern always wins over an earlier one for the same path.
+func (s *Scanner) isExcluded(rel string) bool {
+	excluded := false
+	for _, pattern := range s.Exclude {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			excluded = !negate
+		}
+	}
+	return excluded
+}
+
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RepeatablePatterns implements flag.Value so a CLI can collect repeatable
+// --include/--exclude flags straight into a Scanner's Include/Exclude
+// fields, e.g.:
+//
+//	scanner := &Scanner{Root: root}
+//	flag.Var((*RepeatablePatterns)(&scanner.Include), "include", "glob pattern to include (repeatable)")
+//	flag.Var((*RepeatablePatterns)(&scanner.Exclude), "exclude", "glob pattern to exclude (repeatable)")
+type RepeatablePatterns []string
+
+func (p *RepeatablePatterns) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+func (p *RepeatablePatterns) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
@@ -6,8 +6,6 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
-	log "github.com/sirupsen/logrus"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -19,59 +17,135 @@ type ModuleUsage struct {
 	Locals     map[string]int
 	Modules    map[string]int
 	DataBlocks map[string]int
-	file       *hclwrite.File
+	// Outputs counts references to each declared output from within this
+	// module alone. Outputs are consumed by callers (`module.x.out`), not
+	// from inside their own module, so a count of 0 here does not yet mean
+	// "safe to prune" for a child module with callers outside this
+	// directory; Workspace ties this together across the module tree.
+	Outputs map[string]int
+	// TfVars counts how many loaded tfvars files assign each name, whether
+	// or not a matching variable block exists. Populated from the
+	// conventional terraform.tfvars / *.auto.tfvars files in Path; callers
+	// passing an explicit -var-file should call ApplyTfVars themselves.
+	TfVars map[string]int
+	// Declarations maps a "<kind>.<name>" key (e.g. "variable.foo",
+	// "local.bar", "module.baz", and for data blocks "data." plus the
+	// DataBlocks key, e.g. "data.data.aws_ami.web") to the source range of
+	// the declaring block, for tools that need to point a human (or CI) at
+	// the exact unused declaration.
+	Declarations map[string]hcl.Range
+	// files holds the parsed hclwrite tree of each .tf file, keyed by its
+	// path, so Prune can edit the file a declaration actually came from
+	// instead of a throwaway concatenation of the whole module.
+	files map[string]*hclwrite.File
+	// original holds each file's formatted bytes as they were first parsed,
+	// so Prune can diff against it even after files has been mutated.
+	original map[string][]byte
+	// moduleOutputRefs[instance][output] counts in-module references like
+	// `module.instance.output`. Workspace uses this to tell whether a
+	// child module's output is consumed by any of its callers.
+	moduleOutputRefs map[string]map[string]int
+	// variableDefaults records which declared variables have a `default`
+	// attribute, native HCL or JSON alike, so variableHasDefault doesn't
+	// need to go back to source syntax (which isn't available at all for
+	// JSON, since hclwrite can't parse it).
+	variableDefaults map[string]bool
+	// jsonModuleCalls records the source/args of module blocks declared in
+	// .tf.json files, keyed by instance name, since findModuleCalls can't
+	// re-walk a hclwrite tree for them the way it does for native HCL.
+	jsonModuleCalls map[string]jsonModuleCall
 }
 
 func NewModuleUsage(path string) (*ModuleUsage, error) {
 	m := &ModuleUsage{
-		Path:       path,
-		Variables:  map[string]int{},
-		Locals:     map[string]int{},
-		Modules:    map[string]int{},
-		DataBlocks: map[string]int{},
+		Path:             path,
+		Variables:        map[string]int{},
+		Locals:           map[string]int{},
+		Modules:          map[string]int{},
+		DataBlocks:       map[string]int{},
+		Outputs:          map[string]int{},
+		TfVars:           map[string]int{},
+		Declarations:     map[string]hcl.Range{},
+		files:            map[string]*hclwrite.File{},
+		original:         map[string][]byte{},
+		moduleOutputRefs: map[string]map[string]int{},
+		variableDefaults: map[string]bool{},
+		jsonModuleCalls:  map[string]jsonModuleCall{},
 	}
 
-	src, err := LoadTfModule(path)
+	sources, err := LoadTfModuleFiles(path)
 	if err != nil {
 		return nil, err
 	}
 
-	f, diags := hclwrite.ParseConfig(src, "", hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		return nil, errors.New(path + ":" + diags.Error())
-	}
-
-	m.file = f
-	err = m.processUsage()
-
-	return m, err
-}
+	// Declare every file's blocks before counting references in any of
+	// them. A module's declarations and its references are frequently
+	// split across files (e.g. variable "foo" in variables.tf, var.foo
+	// used in main.tf), and Go's map iteration order over sources is
+	// randomized, so declaring and counting file-by-file in one pass would
+	// nondeterministically drop references to a symbol declared in a file
+	// visited later than the one that references it.
+	nativeBodies := map[string]*hclsyntax.Body{}
+	jsonBodies := map[string]hcl.Body{}
+
+	for filename, src := range sources {
+		if isTfJSON(filename) {
+			body, err := m.declareJSONUsage(filename, src)
+			if err != nil {
+				return nil, err
+			}
+			jsonBodies[filename] = body
+			continue
+		}
 
-func ListTfModules(path string) (map[string]bool, error) {
-	var directories = make(map[string]bool)
+		f, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, errors.New(filename + ":" + diags.Error())
+		}
+		m.files[filename] = f
+		m.original[filename] = hclwrite.Format(f.Bytes())
 
-	err := filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+		syntax, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, errors.New(filename + ":" + diags.Error())
 		}
+		body := syntax.Body.(*hclsyntax.Body)
+		m.declareUsage(body)
+		nativeBodies[filename] = body
+	}
 
-		if filepath.Ext(path) == ".tf" {
-			module := filepath.Dir(path)
-			log.Debugf("Visited: %s\n", module)
-			if _, ok := directories[module]; !ok {
-				directories[module] = true
-			}
+	for _, body := range nativeBodies {
+		m.walkBody(body)
+	}
+	for _, body := range jsonBodies {
+		if err := m.countJSONUsage(body); err != nil {
+			return nil, err
 		}
-		return nil
-	})
+	}
 
+	varFiles, err := DiscoverTfVarFiles(path)
 	if err != nil {
 		return nil, err
 	}
+	for _, varFile := range varFiles {
+		if err := m.ApplyTfVars(varFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
 
-	return directories, nil
+// ListTfModules returns every directory under path containing at least one
+// .tf or .tf.json file. For monorepos that need to skip vendored modules or
+// .terraform/ caches, use a Scanner with Include/Exclude patterns instead.
+func ListTfModules(path string) (map[string]bool, error) {
+	return NewScanner(path).Discover()
 }
 
+// LoadTfModule concatenates every .tf file under path into a single buffer.
+// Prefer LoadTfModuleFiles when the caller needs to know which bytes came
+// from which file, e.g. to edit a file in place.
 func LoadTfModule(path string) ([]byte, error) {
 	var out []byte
 
@@ -92,6 +166,29 @@ func LoadTfModule(path string) ([]byte, error) {
 	return out, nil
 }
 
+// LoadTfModuleFiles reads every .tf and .tf.json file directly under path
+// and returns its contents keyed by full file path.
+func LoadTfModuleFiles(path string) (map[string][]byte, error) {
+	out := map[string][]byte{}
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !isTfFile(file.Name()) {
+			continue
+		}
+		filename := filepath.Join(path, file.Name())
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		out[filename] = data
+	}
+	return out, nil
+}
+
 // parseModuleSource parses module source and returns module name and version.
 func parseModuleSource(a *hclwrite.Attribute) (string, string) {
 	var moduleSourceRegexp = regexp.MustCompile(`(.+)\?ref=v([0-9]+(\.[0-9]+)*(-.*)*)`)
@@ -113,41 +210,132 @@ func parseModuleSource(a *hclwrite.Attribute) (string, string) {
 	return "", ""
 }
 
-func (m ModuleUsage) processUsage() error {
-	body := m.file.Body()
-	bodyStr := string(m.file.Bytes())
-	for _, block := range body.Blocks() {
-		blockType := block.Type()
-		if blockType == "data" {
-			data_type := block.Labels()[0]
-			name := block.Labels()[1]
-			key := fmt.Sprintf("data.%s.%s", data_type, name)
-			m.DataBlocks[key] = countPattern(bodyStr, key)
-		}
-		if blockType == "module" {
-			name := block.Labels()[0]
-			m.Modules[name] = countPattern(bodyStr, fmt.Sprintf(`module\.%s`, name))
-		}
-		if blockType == "variable" {
-			name := block.Labels()[0]
-			m.Variables[name] = countPattern(bodyStr, fmt.Sprintf(`var\.%s\W`, name))
-		} else if blockType == "locals" {
-			attribs := block.Body().Attributes()
-			for attrib := range attribs {
-				m.Locals[attrib] = countPattern(bodyStr, fmt.Sprintf(`local\.%s\W`, attrib))
+// declareUsage records every variable, local, module, and data block found
+// in body, so the symbol exists (at count 0) before any file's references
+// are counted. It must run for every file in a module before walkBody runs
+// for any of them - a declaration in one file must be visible when counting
+// references in another.
+func (m ModuleUsage) declareUsage(body *hclsyntax.Body) {
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "data":
+			dataType := block.Labels[0]
+			name := block.Labels[1]
+			key := fmt.Sprintf("data.%s.%s", dataType, name)
+			m.DataBlocks[key] = 0
+			m.Declarations["data."+key] = block.DefRange()
+		case "module":
+			name := block.Labels[0]
+			m.Modules[name] = 0
+			m.Declarations["module."+name] = block.DefRange()
+		case "variable":
+			name := block.Labels[0]
+			m.Variables[name] = 0
+			m.Declarations["variable."+name] = block.DefRange()
+			if _, hasDefault := block.Body.Attributes["default"]; hasDefault {
+				m.variableDefaults[name] = true
+			}
+		case "output":
+			name := block.Labels[0]
+			m.Outputs[name] = 0
+			m.Declarations["output."+name] = block.DefRange()
+		case "locals":
+			for name, attr := range block.Body.Attributes {
+				m.Locals[name] = 0
+				m.Declarations["local."+name] = attr.NameRange
 			}
 		}
+	}
+}
 
+// walkBody recurses into every nested block (resource, data, module,
+// provisioner, dynamic, ...) and attributes usages from every attribute
+// expression it finds along the way, including cross-references between
+// locals. It must run only after declareUsage has run for every file in the
+// module, so a reference to a symbol declared in a different file is still
+// attributed correctly. Counting from traversals (rather than matching
+// regexes against the raw source) means a match inside a comment, string
+// literal, or heredoc can't be mistaken for a real reference, and `var.foo`
+// is never confused with `var.foobar`.
+func (m ModuleUsage) walkBody(body *hclsyntax.Body) {
+	for _, attr := range body.Attributes {
+		m.countTraversals(attr.Expr.Variables())
+	}
+	for _, block := range body.Blocks {
+		m.walkBody(block.Body)
 	}
+}
 
-	return nil
+func (m ModuleUsage) countTraversals(traversals []hcl.Traversal) {
+	for _, t := range traversals {
+		m.countTraversal(t)
+	}
 }
 
-func countPattern(content string, pattern string) int {
-	regex := regexp.MustCompile(pattern)
-	matches := regex.FindAllStringIndex(content, -1)
+// countTraversal inspects the root of a traversal (var, local, module, data)
+// and the TraverseAttr/TraverseIndex steps that follow it to attribute the
+// reference to the declared symbol it names, e.g. `var.foo[*].bar` is
+// attributed to variable "foo".
+func (m ModuleUsage) countTraversal(t hcl.Traversal) {
+	root, ok := t[0].(hcl.TraverseRoot)
+	if !ok {
+		return
+	}
+
+	switch root.Name {
+	case "var":
+		if name, ok := traversalAttr(t, 1); ok {
+			if _, declared := m.Variables[name]; declared {
+				m.Variables[name]++
+			}
+		}
+	case "local":
+		if name, ok := traversalAttr(t, 1); ok {
+			if _, declared := m.Locals[name]; declared {
+				m.Locals[name]++
+			}
+		}
+	case "module":
+		instance, ok := traversalAttr(t, 1)
+		if !ok {
+			return
+		}
+		if _, declared := m.Modules[instance]; declared {
+			m.Modules[instance]++
+		}
+		if output, ok := traversalAttr(t, 2); ok {
+			if m.moduleOutputRefs[instance] == nil {
+				m.moduleOutputRefs[instance] = map[string]int{}
+			}
+			m.moduleOutputRefs[instance][output]++
+		}
+	case "data":
+		dataType, ok := traversalAttr(t, 1)
+		if !ok {
+			return
+		}
+		name, ok := traversalAttr(t, 2)
+		if !ok {
+			return
+		}
+		key := fmt.Sprintf("data.%s.%s", dataType, name)
+		if _, declared := m.DataBlocks[key]; declared {
+			m.DataBlocks[key]++
+		}
+	}
+}
 
-	return len(matches)
+// traversalAttr returns the name of the TraverseAttr step at index i, if
+// present.
+func traversalAttr(t hcl.Traversal, i int) (string, bool) {
+	if i >= len(t) {
+		return "", false
+	}
+	attr, ok := t[i].(hcl.TraverseAttr)
+	if !ok {
+		return "", false
+	}
+	return attr.Name, true
 }
 
 func (m ModuleUsage) DisplayLocals(unusedOnly bool) error {
@@ -158,12 +346,17 @@ func (m ModuleUsage) DisplayVariables(unusedOnly bool) error {
 	return m.Display(Variables, unusedOnly)
 }
 
+func (m ModuleUsage) DisplayTfVars(unusedOnly bool) error {
+	return m.Display(TfVars, unusedOnly)
+}
+
 type DisplayType string
 
 const (
 	All       DisplayType = "all"
 	Variables DisplayType = "variables"
 	Locals    DisplayType = "locals"
+	TfVars    DisplayType = "tfvars"
 )
 
 func filterUnusedOnly(items map[string]int) map[string]int {
@@ -198,6 +391,10 @@ func (m ModuleUsage) DisplayUnusedSimple(dType DisplayType, unusedOnly bool) err
 }
 
 func (m ModuleUsage) Display(dType DisplayType, unusedOnly bool) error {
+	if dType == TfVars {
+		return m.displayTfVars()
+	}
+
 	variables := map[string]int{}
 	locals := map[string]int{}
 	modules := map[string]int{}
@@ -0,0 +1,198 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestNewModuleUsageCountsAcrossFiles is a regression test for declarations
+// and references being split across files in a module: declareUsage for
+// every file must run before walkBody runs for any of them, or Go's
+// randomized map iteration order over sources would nondeterministically
+// drop references to a symbol declared in a file visited after the one that
+// references it. It runs many times in a single process (go test -count
+// reruns the whole binary, which reseeds map iteration the same way each
+// time, so the repetition has to happen inside the test) to make that
+// nondeterminism reliably surface rather than passing by luck on whichever
+// iteration order this run happened to get.
+func TestNewModuleUsageCountsAcrossFiles(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"variables.tf": `
+variable "foo" {}
+variable "unused" {}
+`,
+		"outputs.tf": `
+output "bar" {
+  value = var.foo
+}
+`,
+		"main.tf": `
+locals {
+  baz = var.foo
+}
+
+module "child" {
+  source = "./modules/child"
+}
+`,
+		"more_outputs.tf": `
+output "baz_out" {
+  value = local.baz
+}
+
+output "child_out" {
+  value = module.child.id
+}
+`,
+	})
+
+	for i := 0; i < 50; i++ {
+		mu, err := NewModuleUsage(dir)
+		if err != nil {
+			t.Fatalf("NewModuleUsage: %v", err)
+		}
+
+		if mu.Variables["foo"] != 2 {
+			t.Fatalf("run %d: Variables[foo] = %d, want 2 (referenced from outputs.tf and main.tf)", i, mu.Variables["foo"])
+		}
+		if mu.Variables["unused"] != 0 {
+			t.Fatalf("run %d: Variables[unused] = %d, want 0", i, mu.Variables["unused"])
+		}
+		if mu.Locals["baz"] != 1 {
+			t.Fatalf("run %d: Locals[baz] = %d, want 1 (referenced from more_outputs.tf)", i, mu.Locals["baz"])
+		}
+		if mu.Modules["child"] != 1 {
+			t.Fatalf("run %d: Modules[child] = %d, want 1 (referenced from more_outputs.tf)", i, mu.Modules["child"])
+		}
+	}
+}
+
+func TestNewModuleUsageCountsTraversals(t *testing.T) {
+	tests := []struct {
+		name        string
+		main        string
+		wantVars    map[string]int
+		wantLocals  map[string]int
+		wantOutputs map[string]int
+		wantModules map[string]int
+	}{
+		{
+			name: "referenced variable is counted",
+			main: `
+variable "foo" {}
+output "bar" {
+  value = var.foo
+}
+`,
+			wantVars:    map[string]int{"foo": 1},
+			wantOutputs: map[string]int{"bar": 0},
+		},
+		{
+			name: "unreferenced variable is zero",
+			main: `
+variable "foo" {}
+variable "unused" {}
+output "bar" {
+  value = var.foo
+}
+`,
+			wantVars:    map[string]int{"foo": 1, "unused": 0},
+			wantOutputs: map[string]int{"bar": 0},
+		},
+		{
+			name: "var.foo does not match var.foobar",
+			main: `
+variable "foo" {}
+variable "foobar" {}
+output "bar" {
+  value = var.foobar
+}
+`,
+			wantVars:    map[string]int{"foo": 0, "foobar": 1},
+			wantOutputs: map[string]int{"bar": 0},
+		},
+		{
+			name: "reference inside a comment or string is not counted",
+			main: `
+variable "foo" {}
+# var.foo is not a real reference
+output "bar" {
+  value = "var.foo"
+}
+`,
+			wantVars:    map[string]int{"foo": 0},
+			wantOutputs: map[string]int{"bar": 0},
+		},
+		{
+			name: "locals can reference each other",
+			main: `
+locals {
+  a = "x"
+  b = local.a
+}
+output "bar" {
+  value = local.b
+}
+`,
+			wantLocals:  map[string]int{"a": 1, "b": 1},
+			wantOutputs: map[string]int{"bar": 0},
+		},
+		{
+			name: "module instance and output references are counted",
+			main: `
+module "vpc" {
+  source = "./modules/vpc"
+}
+output "bar" {
+  value = module.vpc.id
+}
+`,
+			wantModules: map[string]int{"vpc": 1},
+			wantOutputs: map[string]int{"bar": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeModule(t, map[string]string{"main.tf": tt.main})
+
+			mu, err := NewModuleUsage(dir)
+			if err != nil {
+				t.Fatalf("NewModuleUsage: %v", err)
+			}
+
+			for name, want := range tt.wantVars {
+				if got := mu.Variables[name]; got != want {
+					t.Errorf("Variables[%q] = %d, want %d", name, got, want)
+				}
+			}
+			for name, want := range tt.wantLocals {
+				if got := mu.Locals[name]; got != want {
+					t.Errorf("Locals[%q] = %d, want %d", name, got, want)
+				}
+			}
+			for name, want := range tt.wantOutputs {
+				if got := mu.Outputs[name]; got != want {
+					t.Errorf("Outputs[%q] = %d, want %d", name, got, want)
+				}
+			}
+			for name, want := range tt.wantModules {
+				if got := mu.Modules[name]; got != want {
+					t.Errorf("Modules[%q] = %d, want %d", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Change describes one declaration Prune removed (or would remove).
+type Change struct {
+	File string
+	Kind string // "variable", "output", or "local"
+	Name string
+	// Diff holds a unified diff of the file before and after the change,
+	// populated only when Prune is called with writeDiff set.
+	Diff string
+	// Note is set instead of a removal/diff when the declaration lives in a
+	// file Prune can't edit, e.g. "JSON module: fix unavailable" for a
+	// declaration that came from a .tf.json file.
+	Note string
+}
+
+// Prune removes variable, output, and locals-entry declarations whose
+// reference count is zero, mirroring terraform fmt's UX:
+//
+//   - dryRun=true, writeDiff=false behaves like `terraform fmt -check`: it
+//     reports what would be pruned without touching anything on disk.
+//   - writeDiff=true additionally populates Change.Diff with a unified diff,
+//     like `terraform fmt -diff`.
+//   - dryRun=false persists the pruned files to disk, like `terraform fmt`
+//     (or `-write`, which is the default for that command).
+func (m ModuleUsage) Prune(dryRun bool, writeDiff bool) ([]Change, error) {
+	var changes []Change
+	// before tracks each file's bytes as of the most recent change applied
+	// to it so far, seeded from m.original on first touch. Diffing against
+	// this instead of m.original keeps each Change's diff scoped to the one
+	// declaration it removed, rather than re-showing every prior removal in
+	// the same file.
+	before := map[string][]byte{}
+
+	prune := func(kind, name string, remove func(f *hclwrite.File) bool) error {
+		decl, ok := m.Declarations[kind+"."+name]
+		if !ok {
+			return nil
+		}
+
+		filename := decl.Filename
+		if isTfJSON(filename) {
+			changes = append(changes, Change{File: filename, Kind: kind, Name: name, Note: "JSON module: fix unavailable"})
+			return nil
+		}
+
+		f, ok := m.files[filename]
+		if !ok {
+			return fmt.Errorf("no parsed file for %s", filename)
+		}
+		if _, seen := before[filename]; !seen {
+			before[filename] = m.original[filename]
+		}
+
+		if !remove(f) {
+			return nil
+		}
+
+		change := Change{File: filename, Kind: kind, Name: name}
+		after := hclwrite.Format(f.Bytes())
+
+		if writeDiff {
+			diff, err := unifiedDiff(filename, before[filename], after)
+			if err != nil {
+				return err
+			}
+			change.Diff = diff
+		}
+		before[filename] = after
+
+		if !dryRun {
+			if err := os.WriteFile(filename, after, 0644); err != nil {
+				return err
+			}
+		}
+
+		changes = append(changes, change)
+		return nil
+	}
+
+	for name, count := range m.Variables {
+		if count > 0 {
+			continue
+		}
+		if err := prune("variable", name, func(f *hclwrite.File) bool {
+			return removeBlock(f, "variable", name)
+		}); err != nil {
+			return changes, err
+		}
+	}
+
+	for name, count := range m.Outputs {
+		if count > 0 {
+			continue
+		}
+		if err := prune("output", name, func(f *hclwrite.File) bool {
+			return removeBlock(f, "output", name)
+		}); err != nil {
+			return changes, err
+		}
+	}
+
+	for name, count := range m.Locals {
+		if count > 0 {
+			continue
+		}
+		if err := prune("local", name, func(f *hclwrite.File) bool {
+			return removeLocalAttr(f, name)
+		}); err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+// removeBlock deletes the top-level block of the given type whose first
+// label matches name, e.g. `variable "name" { ... }`.
+func removeBlock(f *hclwrite.File, blockType string, name string) bool {
+	body := f.Body()
+	for _, block := range body.Blocks() {
+		if block.Type() != blockType {
+			continue
+		}
+		if len(block.Labels()) != 1 || block.Labels()[0] != name {
+			continue
+		}
+		return body.RemoveBlock(block)
+	}
+	return false
+}
+
+// removeLocalAttr deletes a single entry from a `locals { }` block, removing
+// the whole block if that was its last entry.
+func removeLocalAttr(f *hclwrite.File, name string) bool {
+	body := f.Body()
+	for _, block := range body.Blocks() {
+		if block.Type() != "locals" {
+			continue
+		}
+		if block.Body().GetAttribute(name) == nil {
+			continue
+		}
+		block.Body().RemoveAttribute(name)
+		if len(block.Body().Attributes()) == 0 {
+			body.RemoveBlock(block)
+		}
+		return true
+	}
+	return false
+}
+
+// unifiedDiff shells out to `diff -u`, matching how terraform fmt -diff
+// renders its output, rather than reimplementing a diff algorithm.
+func unifiedDiff(filename string, before, after []byte) (string, error) {
+	if bytes.Equal(before, after) {
+		return "", nil
+	}
+
+	beforeFile, err := os.CreateTemp("", "tfclean-before-*.tf")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(beforeFile.Name())
+	afterFile, err := os.CreateTemp("", "tfclean-after-*.tf")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(afterFile.Name())
+
+	if _, err := beforeFile.Write(before); err != nil {
+		return "", err
+	}
+	if err := beforeFile.Close(); err != nil {
+		return "", err
+	}
+	if _, err := afterFile.Write(after); err != nil {
+		return "", err
+	}
+	if err := afterFile.Close(); err != nil {
+		return "", err
+	}
+
+	out, err := exec.Command("diff", "-u", "--label", filename, "--label", filename, beforeFile.Name(), afterFile.Name()).CombinedOutput()
+	if err != nil {
+		// diff exits 1 when the inputs differ, which is expected here.
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return string(out), nil
+		}
+		return "", err
+	}
+	return string(out), nil
+}
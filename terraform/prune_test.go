@@ -0,0 +1,118 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPruneRemovesUnusedDeclarations(t *testing.T) {
+	main := `
+variable "used" {}
+variable "unused" {}
+
+output "bar" {
+  value = var.used
+}
+
+locals {
+  used_local  = var.used
+  unused_local = "x"
+}
+`
+	dir := writeModule(t, map[string]string{"main.tf": main})
+
+	mu, err := NewModuleUsage(dir)
+	if err != nil {
+		t.Fatalf("NewModuleUsage: %v", err)
+	}
+
+	changes, err := mu.Prune(true /* dryRun */, false)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, change := range changes {
+		got[change.Kind+"."+change.Name] = true
+	}
+	want := []string{"variable.unused", "local.unused_local"}
+	for _, name := range want {
+		if !got[name] {
+			t.Errorf("expected Prune to report removing %s, changes: %+v", name, changes)
+		}
+	}
+	if got["variable.used"] {
+		t.Error("Prune reported removing variable.used, which is referenced")
+	}
+
+	after, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if err != nil {
+		t.Fatalf("reading file after dry run: %v", err)
+	}
+	if !strings.Contains(string(after), `variable "unused"`) {
+		t.Error("dryRun=true should not have modified the file on disk")
+	}
+}
+
+func TestPruneDiffIsScopedPerChange(t *testing.T) {
+	// Two independently prunable variables in one file: each Change's diff
+	// should show only the declaration that particular Change removed, not
+	// every removal made so far in the file.
+	main := `
+variable "a" {}
+variable "b" {}
+`
+	dir := writeModule(t, map[string]string{"main.tf": main})
+
+	mu, err := NewModuleUsage(dir)
+	if err != nil {
+		t.Fatalf("NewModuleUsage: %v", err)
+	}
+
+	changes, err := mu.Prune(true, true)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	var diffA, diffB string
+	for _, change := range changes {
+		switch change.Name {
+		case "a":
+			diffA = change.Diff
+		case "b":
+			diffB = change.Diff
+		}
+	}
+
+	if !strings.Contains(diffA, `-variable "a"`) {
+		t.Errorf("diff for a should remove a's declaration, got:\n%s", diffA)
+	}
+	if strings.Contains(diffA, `-variable "b"`) {
+		t.Errorf("diff for a should not also show b's removal, got:\n%s", diffA)
+	}
+	if !strings.Contains(diffB, `-variable "b"`) {
+		t.Errorf("diff for b should remove b's declaration, got:\n%s", diffB)
+	}
+}
+
+func TestPruneWriteDefersJSONDeclarations(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"main.tf.json": `{"variable": {"unused": {}}}`,
+	})
+
+	mu, err := NewModuleUsage(dir)
+	if err != nil {
+		t.Fatalf("NewModuleUsage: %v", err)
+	}
+
+	changes, err := mu.Prune(false /* dryRun */, false)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Note == "" {
+		t.Fatalf("expected one Change with a Note for the JSON declaration, got %+v", changes)
+	}
+}
@@ -0,0 +1,266 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Kind identifies what sort of Terraform symbol a Result describes.
+type Kind string
+
+const (
+	KindVariable Kind = "variable"
+	KindLocal    Kind = "local"
+	KindModule   Kind = "module"
+	KindOutput   Kind = "output"
+	KindData     Kind = "data"
+)
+
+// Severity is how seriously a Result's finding should be treated.
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Result is one unused declaration, machine-readable enough to drive CI.
+type Result struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Symbol   string   `json:"symbol"`
+	Kind     Kind     `json:"kind"`
+	RefCount int      `json:"refCount"`
+	Severity Severity `json:"severity"`
+}
+
+// Report is every unused-declaration Result found in one module directory.
+type Report struct {
+	Dir     string   `json:"dir"`
+	Results []Result `json:"results"`
+}
+
+// NewReport builds a Report from m's already-computed reference counts,
+// reusing the declaration ranges recorded by declareUsage/declareJSONUsage
+// so results carry an exact file/line/column rather than just a name.
+func NewReport(m *ModuleUsage) *Report {
+	report := &Report{Dir: m.Path}
+
+	add := func(kind Kind, declKey, symbol string, count int) {
+		if count > 0 {
+			return
+		}
+		rng, ok := m.Declarations[declKey]
+		if !ok {
+			return
+		}
+		report.Results = append(report.Results, Result{
+			File:     rng.Filename,
+			Line:     rng.Start.Line,
+			Column:   rng.Start.Column,
+			Symbol:   symbol,
+			Kind:     kind,
+			RefCount: count,
+			Severity: SeverityWarning,
+		})
+	}
+
+	for name, count := range m.Variables {
+		add(KindVariable, "variable."+name, name, count)
+	}
+	for name, count := range m.Locals {
+		add(KindLocal, "local."+name, name, count)
+	}
+	for name, count := range m.Modules {
+		add(KindModule, "module."+name, name, count)
+	}
+	for name, count := range m.Outputs {
+		add(KindOutput, "output."+name, name, count)
+	}
+	for key, count := range m.DataBlocks {
+		add(KindData, "data."+key, key, count)
+	}
+
+	sort.Slice(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	return report
+}
+
+// Reporter renders a set of Reports (one per module directory scanned) to
+// w, in whatever format the implementation is named for.
+type Reporter interface {
+	Write(w io.Writer, reports []*Report) error
+}
+
+// TextReporter renders an emoji-decorated summary in the style of Display,
+// one line per unused declaration with its file:line:column, for a human at
+// a terminal.
+type TextReporter struct{}
+
+func (TextReporter) Write(w io.Writer, reports []*Report) error {
+	for _, report := range reports {
+		if len(report.Results) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "\n \U0001F680 Module: %s\n", report.Dir); err != nil {
+			return err
+		}
+		for _, result := range report.Results {
+			if _, err := fmt.Fprintf(w, " \U0001F449 %s.%s used %d times (%s:%d:%d)\n",
+				result.Kind, result.Symbol, result.RefCount, result.File, result.Line, result.Column); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSONReporter renders reports as a JSON array, for pipelines that want to
+// jq the output rather than scrape stdout.
+type JSONReporter struct{}
+
+func (JSONReporter) Write(w io.Writer, reports []*Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+// SARIFReporter renders reports as a SARIF 2.1.0 log, one run per module
+// directory, so the results can be uploaded to GitHub code scanning or any
+// other SARIF consumer.
+type SARIFReporter struct{}
+
+func (SARIFReporter) Write(w io.Writer, reports []*Report) error {
+	log := SarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+	}
+
+	seenRules := map[string]bool{}
+	var rules []SarifRule
+
+	for _, report := range reports {
+		run := SarifRun{Tool: SarifTool{Driver: SarifDriver{Name: "terraform-cleaner"}}}
+		for _, result := range report.Results {
+			ruleID := sarifRuleID(result.Kind)
+			if !seenRules[ruleID] {
+				seenRules[ruleID] = true
+				rules = append(rules, SarifRule{ID: ruleID, Name: fmt.Sprintf("unused-%s", result.Kind)})
+			}
+
+			run.Results = append(run.Results, SarifResult{
+				RuleID:  ruleID,
+				Level:   sarifLevel(result.Severity),
+				Message: SarifMessage{Text: fmt.Sprintf("%s %q is declared but never referenced", result.Kind, result.Symbol)},
+				Locations: []SarifLocation{{
+					PhysicalLocation: SarifPhysicalLocation{
+						ArtifactLocation: SarifArtifactLocation{URI: result.File},
+						Region:           SarifRegion{StartLine: result.Line, StartColumn: result.Column},
+					},
+				}},
+			})
+		}
+		log.Runs = append(log.Runs, run)
+	}
+
+	for i := range log.Runs {
+		log.Runs[i].Tool.Driver.Rules = rules
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+func sarifRuleID(kind Kind) string {
+	switch kind {
+	case KindVariable:
+		return "TFCLEAN001-unused-variable"
+	case KindLocal:
+		return "TFCLEAN002-unused-local"
+	case KindModule:
+		return "TFCLEAN003-unused-module"
+	case KindOutput:
+		return "TFCLEAN004-unused-output"
+	case KindData:
+		return "TFCLEAN005-unused-data"
+	default:
+		return "TFCLEAN000-unused-symbol"
+	}
+}
+
+func sarifLevel(sev Severity) string {
+	if sev == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// The following types are a minimal subset of the SARIF 2.1.0 object model -
+// just enough to describe an unused-declaration result and where it lives.
+
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool    SarifTool     `json:"tool"`
+	Results []SarifResult `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []SarifRule `json:"rules"`
+}
+
+type SarifRule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type SarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SarifMessage    `json:"message"`
+	Locations []SarifLocation `json:"locations"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+	Region           SarifRegion           `json:"region"`
+}
+
+type SarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
@@ -0,0 +1,144 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// jsonModuleCall captures the source and argument names of a module block
+// declared in a .tf.json file. Native HCL module calls are re-read straight
+// out of the hclwrite tree in findModuleCalls, but JSON has no such tree, so
+// declareJSONUsage records the same information here while it has the
+// parsed body in hand.
+type jsonModuleCall struct {
+	Source string
+	Args   map[string]bool
+}
+
+// isTfFile reports whether name is a Terraform configuration file, native
+// (.tf) or JSON (.tf.json).
+func isTfFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || isTfJSON(name)
+}
+
+// isTfJSON reports whether name is a Terraform JSON configuration file.
+func isTfJSON(name string) bool {
+	return strings.HasSuffix(name, ".tf.json")
+}
+
+// jsonDeclSchema lists the top-level block types declareJSONUsage knows how
+// to declare. It's a PartialContent schema, so any other top-level key
+// (resource, provider, terraform, ...) is simply left out of content.Blocks
+// rather than causing an error.
+var jsonDeclSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "locals"},
+	},
+}
+
+// declareJSONUsage parses a .tf.json file with hcljson and records the same
+// declared-symbol bookkeeping that declareUsage does for native HCL, so the
+// symbol exists before any file's references are counted. It does not
+// populate m.files, since hclwrite can't round-trip JSON syntax - Prune
+// reports those declarations as fix-unavailable instead of silently
+// miscounting them. It returns the parsed body so the caller can run
+// countJSONUsage over it once every file in the module has been declared.
+func (m *ModuleUsage) declareJSONUsage(filename string, src []byte) (hcl.Body, error) {
+	f, diags := hcljson.Parse(src, filename)
+	if diags.HasErrors() {
+		return nil, errors.New(filename + ":" + diags.Error())
+	}
+	body := f.Body
+
+	content, _, diags := body.PartialContent(jsonDeclSchema)
+	if diags.HasErrors() {
+		return nil, errors.New(filename + ":" + diags.Error())
+	}
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "variable":
+			name := block.Labels[0]
+			m.Variables[name] = 0
+			m.Declarations["variable."+name] = block.DefRange
+			attrs, diags := block.Body.JustAttributes()
+			if diags.HasErrors() {
+				return nil, errors.New(filename + ":" + diags.Error())
+			}
+			if _, hasDefault := attrs["default"]; hasDefault {
+				m.variableDefaults[name] = true
+			}
+		case "output":
+			name := block.Labels[0]
+			m.Outputs[name] = 0
+			m.Declarations["output."+name] = block.DefRange
+		case "module":
+			name := block.Labels[0]
+			m.Modules[name] = 0
+			m.Declarations["module."+name] = block.DefRange
+
+			attrs, diags := block.Body.JustAttributes()
+			if diags.HasErrors() {
+				return nil, errors.New(filename + ":" + diags.Error())
+			}
+			call := jsonModuleCall{Args: map[string]bool{}}
+			for attrName, attr := range attrs {
+				if attrName == "source" {
+					if v, diags := attr.Expr.Value(nil); !diags.HasErrors() && v.Type() == cty.String {
+						call.Source = v.AsString()
+					}
+					continue
+				}
+				if !moduleMetaArgs[attrName] {
+					call.Args[attrName] = true
+				}
+			}
+			m.jsonModuleCalls[name] = call
+		case "data":
+			key := fmt.Sprintf("data.%s.%s", block.Labels[0], block.Labels[1])
+			m.DataBlocks[key] = 0
+			m.Declarations["data."+key] = block.DefRange
+		case "locals":
+			attrs, diags := block.Body.JustAttributes()
+			if diags.HasErrors() {
+				return nil, errors.New(filename + ":" + diags.Error())
+			}
+			for name, attr := range attrs {
+				m.Locals[name] = 0
+				m.Declarations["local."+name] = attr.NameRange
+			}
+		}
+	}
+
+	return body, nil
+}
+
+// countJSONUsage walks a .tf.json body collecting hcl.Traversals to count
+// references, the JSON counterpart to walkBody. It must run only after
+// declareJSONUsage has run for every file in the module, so a reference to a
+// symbol declared in a different file is still attributed correctly.
+func (m *ModuleUsage) countJSONUsage(body hcl.Body) error {
+	// The JSON syntax has no way to distinguish attributes from blocks, so
+	// JustAttributes on the whole body just hands back every top-level
+	// member, and each member's Variables() recurses through its entire
+	// nested value - which is all we need to count every reference in the
+	// file in one pass.
+	attrs, diags := body.JustAttributes()
+	if diags.HasErrors() {
+		return errors.New(diags.Error())
+	}
+	for _, attr := range attrs {
+		m.countTraversals(attr.Expr.Variables())
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
+// LoadTfVars parses a single .tfvars or .tfvars.json file and returns the
+// source range of each top-level attribute, keyed by name, so callers can
+// cross-check assignments against the module's declared variables.
+func LoadTfVars(path string) (map[string]hcl.Range, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(path, ".json") {
+		return parseTfVarsJSON(path, src)
+	}
+
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s: %s", path, diags.Error())
+	}
+
+	vars := map[string]hcl.Range{}
+	for name, attr := range f.Body.(*hclsyntax.Body).Attributes {
+		vars[name] = attr.NameRange
+	}
+	return vars, nil
+}
+
+func parseTfVarsJSON(path string, src []byte) (map[string]hcl.Range, error) {
+	f, diags := hcljson.Parse(src, path)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s: %s", path, diags.Error())
+	}
+
+	attrs, diags := f.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s: %s", path, diags.Error())
+	}
+
+	vars := map[string]hcl.Range{}
+	for name, attr := range attrs {
+		vars[name] = attr.NameRange
+	}
+	return vars, nil
+}
+
+// DiscoverTfVarFiles returns the tfvars files Terraform loads automatically
+// for dir - terraform.tfvars(.json) followed by *.auto.tfvars(.json) files
+// in lexical order - matching the order Terraform itself applies them.
+func DiscoverTfVarFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var primary []string
+	var auto []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case name == "terraform.tfvars" || name == "terraform.tfvars.json":
+			primary = append(primary, filepath.Join(dir, name))
+		case strings.HasSuffix(name, ".auto.tfvars") || strings.HasSuffix(name, ".auto.tfvars.json"):
+			auto = append(auto, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(primary)
+	sort.Strings(auto)
+	return append(primary, auto...), nil
+}
+
+// ApplyTfVars loads path and increments m.TfVars for each name it assigns,
+// whether or not a matching variable is declared. Call it once for every conventional tfvars file
+// (DiscoverTfVarFiles) and once more per explicit -var-file argument.
+func (m *ModuleUsage) ApplyTfVars(path string) error {
+	assignments, err := LoadTfVars(path)
+	if err != nil {
+		return err
+	}
+	for name := range assignments {
+		m.TfVars[name]++
+	}
+	return nil
+}
+
+// OrphanTfVars returns tfvars keys that were assigned but have no matching
+// `variable` block in the module.
+func (m ModuleUsage) OrphanTfVars() []string {
+	var orphans []string
+	for name := range m.TfVars {
+		if _, declared := m.Variables[name]; !declared {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// RequiredButUnset returns declared variables that have no default value
+// and are never assigned by any loaded tfvars file.
+func (m ModuleUsage) RequiredButUnset() []string {
+	var missing []string
+	for name := range m.Variables {
+		if m.TfVars[name] > 0 {
+			continue
+		}
+		if variableHasDefault(&m, name) {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func (m ModuleUsage) displayTfVars() error {
+	orphans := m.OrphanTfVars()
+	missing := m.RequiredButUnset()
+
+	if len(orphans)+len(missing) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n \U0001F680 Module: %s\n", m.Path)
+
+	if len(orphans) > 0 {
+		fmt.Printf(" \U0001F449 %d orphan tfvars assignments found\n", len(orphans))
+		for _, name := range orphans {
+			fmt.Printf("%s : assigned in tfvars, no matching variable block\n", name)
+		}
+	}
+
+	if len(missing) > 0 {
+		fmt.Printf(" \U0001F449 %d variables required but unset found\n", len(missing))
+		for _, name := range missing {
+			fmt.Printf("%s : no default, not set via tfvars\n", name)
+		}
+	}
+
+	return nil
+}
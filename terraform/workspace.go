@@ -0,0 +1,257 @@
+package terraform
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// moduleMetaArgs are module block arguments that configure the call itself
+// rather than supplying a value to one of the child module's variables.
+var moduleMetaArgs = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"providers":  true,
+	"count":      true,
+	"for_each":   true,
+	"depends_on": true,
+}
+
+// ModuleCall is one `module "name" { ... }` block, resolved to the directory
+// it calls when its source is a local path.
+type ModuleCall struct {
+	CallerDir    string
+	InstanceName string
+	// SourceDir is the resolved local directory the call targets, or "" if
+	// the source is a registry/git address Workspace can't walk onto disk.
+	SourceDir string
+	Range     hcl.Range
+	// Args holds the argument names passed in the module block body, i.e.
+	// the child variables this call supplies a value for.
+	Args map[string]bool
+}
+
+// Finding names a single declared symbol and where it lives, for workspace
+// diagnostics that reference counts alone can't produce (e.g. a variable no
+// caller ever supplies).
+type Finding struct {
+	Dir   string
+	Name  string
+	Range hcl.Range
+}
+
+// Workspace analyses every Terraform module under Root together, resolving
+// `module "name" { source = "..." }` blocks to the directories they call so
+// usage can be propagated across module boundaries: a child variable is used
+// if any caller supplies it, and a child output is used if any caller
+// references `module.name.that_output`.
+type Workspace struct {
+	Root    string
+	Modules map[string]*ModuleUsage // directory -> usage
+	Calls   []ModuleCall
+}
+
+// NewWorkspace walks root, builds a ModuleUsage for every directory
+// containing .tf files, and resolves the module call graph between them.
+func NewWorkspace(root string) (*Workspace, error) {
+	dirs, err := ListTfModules(root)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workspace{Root: root, Modules: map[string]*ModuleUsage{}}
+	for dir := range dirs {
+		mu, err := NewModuleUsage(dir)
+		if err != nil {
+			return nil, err
+		}
+		w.Modules[dir] = mu
+	}
+
+	for dir, mu := range w.Modules {
+		w.Calls = append(w.Calls, findModuleCalls(dir, mu)...)
+	}
+
+	return w, nil
+}
+
+// findModuleCalls collects every module block declared in mu, native HCL and
+// JSON alike, resolving local-path sources relative to dir.
+func findModuleCalls(dir string, mu *ModuleUsage) []ModuleCall {
+	var calls []ModuleCall
+
+	for name, raw := range mu.jsonModuleCalls {
+		calls = append(calls, ModuleCall{
+			CallerDir:    dir,
+			InstanceName: name,
+			SourceDir:    resolveLocalModuleSource(dir, raw.Source),
+			Range:        mu.Declarations["module."+name],
+			Args:         raw.Args,
+		})
+	}
+
+	for _, f := range mu.files {
+		for _, block := range f.Body().Blocks() {
+			if block.Type() != "module" || len(block.Labels()) != 1 {
+				continue
+			}
+
+			instanceName := block.Labels()[0]
+			call := ModuleCall{
+				CallerDir:    dir,
+				InstanceName: instanceName,
+				Range:        mu.Declarations["module."+instanceName],
+				Args:         map[string]bool{},
+			}
+
+			if source := block.Body().GetAttribute("source"); source != nil {
+				name, _ := parseModuleSource(source)
+				call.SourceDir = resolveLocalModuleSource(dir, name)
+			}
+
+			for name := range block.Body().Attributes() {
+				if !moduleMetaArgs[name] {
+					call.Args[name] = true
+				}
+			}
+
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// resolveLocalModuleSource resolves a module source address to a directory
+// on disk, if it's a local path (the only kind Workspace can walk onto
+// disk); registry and VCS sources are left unresolved.
+func resolveLocalModuleSource(callerDir, source string) string {
+	if source == "" {
+		return ""
+	}
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+		return ""
+	}
+	return filepath.Clean(filepath.Join(callerDir, source))
+}
+
+// calleeDirs returns the set of directories targeted by at least one local
+// module call.
+func (w *Workspace) calleeDirs() map[string]bool {
+	callees := map[string]bool{}
+	for _, call := range w.Calls {
+		if call.SourceDir != "" {
+			callees[call.SourceDir] = true
+		}
+	}
+	return callees
+}
+
+// UnsuppliedVariables returns variables declared by a called child module
+// that no caller ever supplies an argument for and that have no default.
+func (w *Workspace) UnsuppliedVariables() []Finding {
+	supplied := map[string]map[string]bool{}
+	for _, call := range w.Calls {
+		if call.SourceDir == "" {
+			continue
+		}
+		if supplied[call.SourceDir] == nil {
+			supplied[call.SourceDir] = map[string]bool{}
+		}
+		for name := range call.Args {
+			supplied[call.SourceDir][name] = true
+		}
+	}
+
+	var findings []Finding
+	for dir := range w.calleeDirs() {
+		mu := w.Modules[dir]
+		if mu == nil {
+			continue
+		}
+		for name := range mu.Variables {
+			if supplied[dir][name] || variableHasDefault(mu, name) {
+				continue
+			}
+			findings = append(findings, Finding{Dir: dir, Name: name, Range: mu.Declarations["variable."+name]})
+		}
+	}
+	return findings
+}
+
+// UnconsumedOutputs returns outputs declared by a called child module that
+// no caller ever references as `module.instance.output`.
+func (w *Workspace) UnconsumedOutputs() []Finding {
+	consumed := map[string]map[string]bool{}
+	for _, call := range w.Calls {
+		if call.SourceDir == "" {
+			continue
+		}
+		caller := w.Modules[call.CallerDir]
+		if caller == nil {
+			continue
+		}
+		for name := range caller.moduleOutputRefs[call.InstanceName] {
+			if consumed[call.SourceDir] == nil {
+				consumed[call.SourceDir] = map[string]bool{}
+			}
+			consumed[call.SourceDir][name] = true
+		}
+	}
+
+	var findings []Finding
+	for dir := range w.calleeDirs() {
+		mu := w.Modules[dir]
+		if mu == nil {
+			continue
+		}
+		for name := range mu.Outputs {
+			if consumed[dir][name] {
+				continue
+			}
+			findings = append(findings, Finding{Dir: dir, Name: name, Range: mu.Declarations["output."+name]})
+		}
+	}
+	return findings
+}
+
+// UnreferencedModuleInstances returns module calls whose instance name
+// (`module.instance`) is never referenced anywhere else in the caller.
+func (w *Workspace) UnreferencedModuleInstances() []Finding {
+	var findings []Finding
+	for dir, mu := range w.Modules {
+		for name, count := range mu.Modules {
+			if count == 0 {
+				findings = append(findings, Finding{Dir: dir, Name: name, Range: mu.Declarations["module."+name]})
+			}
+		}
+	}
+	return findings
+}
+
+// ConsumersOfOutput answers "who consumes this?" for a child module's
+// output: it returns every call in the workspace that targets dir and
+// references output name via `module.instance.name`.
+func (w *Workspace) ConsumersOfOutput(dir, name string) []ModuleCall {
+	var consumers []ModuleCall
+	for _, call := range w.Calls {
+		if call.SourceDir != dir {
+			continue
+		}
+		caller := w.Modules[call.CallerDir]
+		if caller == nil {
+			continue
+		}
+		if caller.moduleOutputRefs[call.InstanceName][name] > 0 {
+			consumers = append(consumers, call)
+		}
+	}
+	return consumers
+}
+
+// variableHasDefault reports whether the variable named name declares a
+// default value, native HCL or JSON alike.
+func variableHasDefault(mu *ModuleUsage, name string) bool {
+	return mu.variableDefaults[name]
+}